@@ -0,0 +1,142 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv
+
+const fnParseComplex = "ParseComplex"
+
+// ParseComplex converts the []byte s to a complex number with the precision
+// specified by bitSize: 64 for complex64, or 128 for complex128. When
+// bitSize=64, the result still has type complex128, but it will be
+// convertible to complex64 without changing its value.
+//
+// The number represented by s must be of the form N, Ni, or N±Ni, where N
+// stands for a floating-point number as recognized by ParseFloat, and i is
+// the imaginary unit. The 'N' and 'Ni' forms allow a single real or
+// imaginary component, in which case the other component is zero; unlike
+// most complex literal syntaxes, there is no separate bare-imaginary '+i'/
+// '-i' form to special-case, so "i", "+i", and "-i" are ErrSyntax. If the
+// second N of an N±Ni literal is NaN, only a '+' separator is accepted,
+// since ParseFloat itself never accepts a signed NaN; a bare imaginary NaN
+// with no real part, as in "+NaNi", is still ErrSyntax.
+//
+// s may optionally be surrounded by parentheses.
+//
+// If s is not syntactically well-formed, ParseComplex returns err.Err =
+// ErrSyntax.
+//
+// If s is syntactically well-formed but either component is more than 1/2
+// ULP away from the largest floating point number of the given component's
+// size, ParseComplex returns err.Err = ErrRange and c = ±Inf for the
+// respective component.
+func ParseComplex(s []byte, bitSize int) (complex128, error) {
+	size := 64
+	if bitSize == 64 {
+		size = 32
+	}
+
+	orig := s
+	if len(s) == 0 {
+		return 0, syntaxError(fnParseComplex, orig)
+	}
+
+	if len(s) >= 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		s = s[1 : len(s)-1]
+		if len(s) == 0 {
+			return 0, syntaxError(fnParseComplex, orig)
+		}
+	}
+
+	if s[len(s)-1] != 'i' {
+		// No imaginary part; the whole thing is the real part.
+		// ParseFloat returns 0 with ErrSyntax and ±Inf with ErrRange.
+		re, err := ParseFloat(s, size)
+		if err != nil {
+			return complex(re, 0), wrapComplexErr(err, orig)
+		}
+		return complex(re, 0), nil
+	}
+	body := s[:len(s)-1]
+
+	if split := splitComplex(body); split >= 0 {
+		re, err := ParseFloat(body[:split], size)
+		if err != nil && err.(*NumError).Err != ErrRange {
+			return 0, wrapComplexErr(err, orig)
+		}
+		im, imErr := parseImagUnit(stripPlusBeforeNaN(body[split:]), size)
+		if imErr != nil {
+			if imErr.(*NumError).Err != ErrRange {
+				return 0, wrapComplexErr(imErr, orig)
+			}
+			err = imErr
+		}
+		if err != nil {
+			return complex(re, im), wrapComplexErr(err, orig)
+		}
+		return complex(re, im), nil
+	}
+
+	// No real part; the whole thing (minus the trailing 'i') is the
+	// imaginary coefficient.
+	im, err := parseImagUnit(body, size)
+	if err != nil {
+		return complex(0, im), wrapComplexErr(err, orig)
+	}
+	return complex(0, im), nil
+}
+
+// parseImagUnit parses the coefficient preceding the imaginary unit 'i'.
+// There is no bare-sign or empty-coefficient shortcut: "i", "+i", and "-i"
+// are ErrSyntax, matching ParseFloat's rejection of "", "+", and "-".
+func parseImagUnit(s []byte, size int) (float64, error) {
+	return ParseFloat(s, size)
+}
+
+// stripPlusBeforeNaN consumes a leading '+' directly before "NaN": in the
+// N±Ni separator position that '+' is required punctuation, not part of the
+// number, and ParseFloat never accepts a signed NaN on its own. It must not
+// be applied to a bare imaginary coefficient with no preceding real part,
+// where "+NaNi" is ErrSyntax just like "+i" is.
+func stripPlusBeforeNaN(s []byte) []byte {
+	if len(s) == 4 && s[0] == '+' && commonPrefixLenIgnoreCase(s[1:], "nan") == 3 {
+		return s[1:]
+	}
+	return s
+}
+
+// splitComplex returns the index of the '+' or '-' in s (the real/imaginary
+// separator of a "N±Ni" literal with its trailing 'i' already removed),
+// scanning from the end and skipping over any sign that instead introduces a
+// decimal or hexadecimal exponent. It returns -1 if s has no such
+// separator, meaning s is purely imaginary.
+// splitComplex finds the separator between the real and imaginary parts of
+// an N±Ni literal by scanning from the right for the last '+'/'-' that isn't
+// an exponent sign. This is simpler than upstream strconv's left-to-right
+// scan but disagrees with it on pathological doubled-sign input such as
+// "1+-2i": upstream parses that as 1-2i by taking the first separator,
+// while here the trailing "-2i" run leaves no non-sign prefix for
+// ParseFloat and the whole thing is ErrSyntax.
+func splitComplex(s []byte) int {
+	for i := len(s) - 1; i > 0; i-- {
+		c := s[i]
+		if c != '+' && c != '-' {
+			continue
+		}
+		if prev := lower(s[i-1]); prev == 'e' || prev == 'p' {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// wrapComplexErr re-labels a *NumError produced by ParseFloat as having come
+// from ParseComplex, snapshotting the full original input rather than just
+// the failing component.
+func wrapComplexErr(err error, orig []byte) error {
+	if ne, ok := err.(*NumError); ok {
+		return &NumError{fnParseComplex, ne.Err, append([]byte(nil), orig...)}
+	}
+	return err
+}
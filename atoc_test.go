@@ -0,0 +1,179 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv_test
+
+import (
+	"bytes"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	. "github.com/hnakamur/bytesconv"
+)
+
+type atocTest struct {
+	in  []byte
+	out complex128
+	err error
+}
+
+func TestParseComplex(t *testing.T) {
+	nan := math.NaN()
+	inf := math.Inf(1)
+	tests := []atocTest{
+		// Decimal
+		{[]byte("0"), 0, nil},
+		{[]byte("0i"), 0, nil},
+		{[]byte("1"), 1, nil},
+		{[]byte("1i"), complex(0, 1), nil},
+		{[]byte("+1"), 1, nil},
+		{[]byte("+1i"), complex(0, 1), nil},
+		{[]byte("-1"), -1, nil},
+		{[]byte("-1i"), complex(0, -1), nil},
+		{[]byte("+1.5"), 1.5, nil},
+		{[]byte("1.5i"), complex(0, 1.5), nil},
+		{[]byte("1.5+1.5i"), complex(1.5, 1.5), nil},
+		{[]byte("1.5-1.5i"), complex(1.5, -1.5), nil},
+		{[]byte("-1.5+1.5i"), complex(-1.5, 1.5), nil},
+		{[]byte("-1.5-1.5i"), complex(-1.5, -1.5), nil},
+		{[]byte("-1.5-1.5e2i"), complex(-1.5, -150), nil},
+
+		// Hexadecimal
+		{[]byte("0x1p0+0x1p-1i"), complex(1, 0.5), nil},
+
+		// Parentheses
+		{[]byte("(1)"), 1, nil},
+		{[]byte("(1i)"), complex(0, 1), nil},
+		{[]byte("(1+1.5i)"), complex(1, 1.5), nil},
+
+		// NaNs and Infs
+		{[]byte("NaN"), complex(nan, 0), nil},
+		{[]byte("NaNi"), complex(0, nan), nil},
+		{[]byte("1+NaNi"), complex(1, nan), nil},
+		{[]byte("NaN+NaNi"), complex(nan, nan), nil},
+		{[]byte("+Inf+Infi"), complex(inf, inf), nil},
+		{[]byte("-Inf-Infi"), complex(-inf, -inf), nil},
+
+		// Errors
+		{[]byte(""), 0, ErrSyntax},
+		{[]byte("1+"), 0, ErrSyntax},
+		{[]byte("1+1"), 0, ErrSyntax},
+		{[]byte("1a"), 0, ErrSyntax},
+		{[]byte("()"), 0, ErrSyntax},
+		{[]byte("(1"), 0, ErrSyntax},
+		{[]byte("1)"), 0, ErrSyntax},
+
+		// Bare sign before i: there is no bare-imaginary shortcut, so these
+		// are ErrSyntax just like ParseFloat rejects "", "+", and "-".
+		{[]byte("i"), 0, ErrSyntax},
+		{[]byte("+i"), 0, ErrSyntax},
+		{[]byte("-i"), 0, ErrSyntax},
+
+		// A '-' between components is not accepted before NaN: ParseFloat
+		// never accepts a signed NaN, and only '+' gets the separator
+		// workaround.
+		{[]byte("1-NaNi"), 0, ErrSyntax},
+
+		// The '+'-before-NaN workaround applies only to the N±Ni separator,
+		// not to a bare imaginary coefficient with no preceding real part.
+		{[]byte("+NaNi"), 0, ErrSyntax},
+
+		// Only lowercase 'i' marks the imaginary part, matching Go's
+		// imaginary-literal syntax and FormatComplex's own output; a
+		// trailing 'I' is not a valid substitute.
+		{[]byte("4I"), 0, ErrSyntax},
+		{[]byte("9-12I"), 0, ErrSyntax},
+	}
+	for _, test := range tests {
+		got, err := ParseComplex(test.in, 128)
+		wantErr := test.err
+		if wantErr != nil {
+			if ne, ok := err.(*NumError); !ok || ne.Err != wantErr {
+				t.Errorf("ParseComplex(%q, 128) = %v, %v want err %v", test.in, got, err, wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseComplex(%q, 128) = %v, %v want %v, nil", test.in, got, err, test.out)
+			continue
+		}
+		if !cmplxEqual(got, test.out) {
+			t.Errorf("ParseComplex(%q, 128) = %v, want %v", test.in, got, test.out)
+		}
+	}
+}
+
+// cmplxEqual reports whether a and b are equal, treating NaN components as
+// equal to each other the way the atof NaN tests do.
+func cmplxEqual(a, b complex128) bool {
+	re := real(a) == real(b) || (math.IsNaN(real(a)) && math.IsNaN(real(b)))
+	im := imag(a) == imag(b) || (math.IsNaN(imag(a)) && math.IsNaN(imag(b)))
+	return re && im
+}
+
+func TestParseComplexBitSize(t *testing.T) {
+	got, err := ParseComplex([]byte("0.1+0.1i"), 64)
+	if err != nil {
+		t.Fatalf("ParseComplex(%q, 64) returned error %v", "0.1+0.1i", err)
+	}
+	f32 := float64(0.1)
+	want := complex(float64(float32(f32)), float64(float32(f32)))
+	if got != want {
+		t.Errorf("ParseComplex(%q, 64) = %v, want %v", "0.1+0.1i", got, want)
+	}
+}
+
+func TestFormatComplex(t *testing.T) {
+	tests := []struct {
+		c      complex128
+		fmt    byte
+		prec   int
+		bits   int
+		result []byte
+	}{
+		{0, 'g', -1, 128, []byte("(0+0i)")},
+		{1, 'g', -1, 128, []byte("(1+0i)")},
+		{complex(0, 1), 'g', -1, 128, []byte("(0+1i)")},
+		{complex(1, 1), 'g', -1, 128, []byte("(1+1i)")},
+		{complex(1.5, -1.5), 'g', -1, 128, []byte("(1.5-1.5i)")},
+		{complex(-1.5, 1.5), 'g', -1, 128, []byte("(-1.5+1.5i)")},
+		{complex(1, 2), 'e', 2, 128, []byte("(1.00e+00+2.00e+00i)")},
+		{complex(1, 2), 'f', 2, 128, []byte("(1.00+2.00i)")},
+	}
+	for _, test := range tests {
+		got := FormatComplex(test.c, test.fmt, test.prec, test.bits)
+		if !bytes.Equal(got, test.result) {
+			t.Errorf("FormatComplex(%v, %q, %d, %d) = %s, want %s",
+				test.c, test.fmt, test.prec, test.bits, got, test.result)
+		}
+	}
+}
+
+func TestFormatComplexInvalidBitSize(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for invalid bitSize")
+		}
+	}()
+	FormatComplex(1+1i, 'g', -1, 100)
+}
+
+func TestComplexRoundTrip(t *testing.T) {
+	cases := []complex128{
+		0, 1, -1, 1i, -1i, 1 + 1i, -1 - 1i,
+		complex(1.5, -2.5), complex(1e300, -1e-300),
+	}
+	for _, c := range cases {
+		s := FormatComplex(c, 'g', -1, 128)
+		got, err := ParseComplex(s, 128)
+		if err != nil {
+			t.Errorf("ParseComplex(FormatComplex(%v)) failed: %v", c, err)
+			continue
+		}
+		if cmplx.Abs(got-c) > 1e-9*cmplx.Abs(c) {
+			t.Errorf("round trip of %v produced %v via %q", c, got, s)
+		}
+	}
+}
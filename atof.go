@@ -0,0 +1,592 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv
+
+import "math"
+
+// optimize controls whether the fast paths in ParseFloat and FormatFloat are
+// used. SetOptimize exists so tests and benchmarks can exercise the slow
+// paths directly; production callers have no reason to touch it.
+var optimize = true
+
+// SetOptimize controls whether the fast parsing and formatting algorithms
+// are used. It returns the previous setting.
+func SetOptimize(b bool) bool {
+	old := optimize
+	optimize = b
+	return old
+}
+
+type floatInfo struct {
+	mantbits uint
+	expbits  uint
+	bias     int
+}
+
+var float32info = floatInfo{23, 8, -127}
+var float64info = floatInfo{52, 11, -1023}
+
+const fnParseFloat = "ParseFloat"
+
+// ParseFloat converts the []byte s to a floating-point number with the
+// precision specified by bitSize: 32 for float32, or 64 for float64. When
+// bitSize=32, the result still has type float64, but it will be convertible
+// to float32 without changing its value.
+//
+// ParseFloat accepts decimal and hexadecimal floating-point numbers as
+// defined by the Go syntax for floating-point literals. If s is well-formed
+// and near a valid floating-point number, ParseFloat returns the nearest
+// floating-point number rounded using IEEE754 unbiased rounding, with
+// ErrRange set if the result exceeds the range of the given bit size.
+//
+// ParseFloat recognizes the string "NaN", and the (possibly signed) strings
+// "Inf" and "Infinity" as their respective special floating-point values. It
+// ignores case when matching.
+//
+// If s is not syntactically well-formed, ParseFloat returns err.Err =
+// ErrSyntax.
+func ParseFloat(s []byte, bitSize int) (float64, error) {
+	if bitSize == 32 {
+		f, err := atof32(s)
+		return float64(f), err
+	}
+	return atof64(s)
+}
+
+func atof64(s []byte) (f float64, err error) {
+	if val, n, ok := special(s); ok && n == len(s) {
+		return val, nil
+	}
+
+	mantissa, exp, neg, trunc, hex, n, ok := readFloat(s)
+	if !ok || n != len(s) {
+		return 0, syntaxError(fnParseFloat, s)
+	}
+
+	if hex {
+		bits, ovf := atofHexBits(&float64info, mantissa, exp, trunc)
+		f = math.Float64frombits(setSign(bits, neg, &float64info))
+		if ovf {
+			return f, rangeError(fnParseFloat, s)
+		}
+		return f, nil
+	}
+
+	if optimize && !trunc {
+		if bits, ok := eiselLemire(&float64info, mantissa, exp, neg); ok {
+			return math.Float64frombits(bits), nil
+		}
+	}
+
+	var d decimal
+	if !d.set(s) {
+		return 0, syntaxError(fnParseFloat, s)
+	}
+	b, ovf := d.floatBits(&float64info)
+	f = math.Float64frombits(b)
+	if ovf {
+		err = rangeError(fnParseFloat, s)
+	}
+	return f, err
+}
+
+func atof32(s []byte) (f float32, err error) {
+	if val, n, ok := special(s); ok && n == len(s) {
+		return float32(val), nil
+	}
+
+	mantissa, exp, neg, trunc, hex, n, ok := readFloat(s)
+	if !ok || n != len(s) {
+		return 0, syntaxError(fnParseFloat, s)
+	}
+
+	if hex {
+		bits, ovf := atofHexBits(&float32info, mantissa, exp, trunc)
+		f = math.Float32frombits(uint32(setSign(bits, neg, &float32info)))
+		if ovf {
+			return f, rangeError(fnParseFloat, s)
+		}
+		return f, nil
+	}
+
+	if optimize && !trunc {
+		if bits, ok := eiselLemire(&float32info, mantissa, exp, neg); ok {
+			return math.Float32frombits(uint32(bits)), nil
+		}
+	}
+
+	var d decimal
+	if !d.set(s) {
+		return 0, syntaxError(fnParseFloat, s)
+	}
+	b, ovf := d.floatBits(&float32info)
+	f = math.Float32frombits(uint32(b))
+	if ovf {
+		err = rangeError(fnParseFloat, s)
+	}
+	return f, err
+}
+
+// setSign applies the parsed sign to the unsigned-magnitude bits produced by
+// atofHexBits. The bits occupy only mantbits+expbits bits; the sign bit sits
+// just above them.
+func setSign(bits uint64, neg bool, flt *floatInfo) uint64 {
+	if neg {
+		bits |= 1 << flt.mantbits << flt.expbits
+	}
+	return bits
+}
+
+// lower(c) is a lower-case letter if c is an upper-case letter.
+func lower(c byte) byte {
+	return c | ('x' - 'X')
+}
+
+// special returns the floating-point value for the leading special value
+// ("inf", "infinity", or "nan", in any combination of cases) in s, and the
+// number of bytes it consumed. "inf" and "infinity" may carry a sign; "nan"
+// may not.
+func special(s []byte) (f float64, n int, ok bool) {
+	if len(s) == 0 {
+		return 0, 0, false
+	}
+	sign := 1
+	nsign := 0
+	switch s[0] {
+	case '+', '-':
+		if s[0] == '-' {
+			sign = -1
+		}
+		nsign = 1
+		s = s[1:]
+		fallthrough
+	case 'i', 'I':
+		n := commonPrefixLenIgnoreCase(s, "infinity")
+		// Both "inf" and "infinity" are ok.
+		if 3 <= n && n < 8 {
+			n = 3
+		}
+		if n == 3 || n == 8 {
+			return math.Inf(sign), nsign + n, true
+		}
+	case 'n', 'N':
+		if commonPrefixLenIgnoreCase(s, "nan") == 3 {
+			return math.NaN(), 3, true
+		}
+	}
+	return 0, 0, false
+}
+
+func commonPrefixLenIgnoreCase(s []byte, prefix string) int {
+	n := len(prefix)
+	if n > len(s) {
+		n = len(s)
+	}
+	for i := 0; i < n; i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != prefix[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// readFloat reads a decimal or hexadecimal mantissa and exponent from a
+// floating-point representation in s; the number may be followed by other
+// bytes. readFloat reports the number of bytes consumed (n) and whether the
+// number is syntactically valid (ok). It does not handle Inf or NaN, which
+// are recognized by special before readFloat is tried.
+//
+// hex reports whether s used the "0x" mantissa form; for hex floats,
+// mantissa and exp are such that the value equals mantissa * 2**exp, with
+// any digits beyond the first 16 hex digits of the mantissa folded into
+// trunc rather than mantissa (mirroring the decimal case, where digits
+// beyond the first 19 are similarly dropped).
+func readFloat(s []byte) (mantissa uint64, exp int, neg, trunc, hex bool, n int, ok bool) {
+	i := 0
+
+	// optional sign
+	if i >= len(s) {
+		return
+	}
+	switch {
+	case s[i] == '+':
+		i++
+	case s[i] == '-':
+		neg = true
+		i++
+	}
+
+	// digits
+	base := uint64(10)
+	maxMantDigits := 19 // 10^19 fits in a uint64
+	expChar := byte('e')
+	if i+2 <= len(s) && s[i] == '0' && lower(s[i+1]) == 'x' {
+		base = 16
+		maxMantDigits = 16 // 16^16 fits in a uint64
+		i += 2
+		expChar = 'p'
+		hex = true
+	}
+	sawdot := false
+	sawdigits := false
+	nd := 0
+	ndMant := 0
+	dp := 0
+	underscores := false
+loop:
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_':
+			underscores = true
+			continue
+
+		case c == '.':
+			if sawdot {
+				break loop
+			}
+			sawdot = true
+			dp = nd
+			continue
+
+		case '0' <= c && c <= '9':
+			sawdigits = true
+			if c == '0' && nd == 0 { // ignore leading zeros
+				dp--
+				continue
+			}
+			nd++
+			if ndMant < maxMantDigits {
+				mantissa *= base
+				mantissa += uint64(c - '0')
+				ndMant++
+			} else if c != '0' {
+				trunc = true
+			}
+			continue
+
+		case base == 16 && 'a' <= lower(c) && lower(c) <= 'f':
+			sawdigits = true
+			nd++
+			if ndMant < maxMantDigits {
+				mantissa *= 16
+				mantissa += uint64(lower(c) - 'a' + 10)
+				ndMant++
+			} else {
+				trunc = true
+			}
+			continue
+		}
+		break loop
+	}
+	if !sawdigits {
+		return
+	}
+	if !sawdot {
+		dp = nd
+	}
+
+	if base == 16 {
+		dp *= 4
+		ndMant *= 4
+	}
+
+	// optional exponent moves decimal point.
+	// if we read a hex number, the exponent must be present, to convert
+	// it to a decimal-like representation.
+	if i < len(s) && lower(s[i]) == expChar {
+		i++
+		if i >= len(s) {
+			return
+		}
+		esign := 1
+		if s[i] == '+' {
+			i++
+		} else if s[i] == '-' {
+			i++
+			esign = -1
+		}
+		if i >= len(s) || s[i] < '0' || s[i] > '9' {
+			return
+		}
+		e := 0
+		for ; i < len(s) && ('0' <= s[i] && s[i] <= '9' || s[i] == '_'); i++ {
+			if s[i] == '_' {
+				underscores = true
+				continue
+			}
+			if e < 10000 {
+				e = e*10 + int(s[i]-'0')
+			}
+		}
+		dp += e * esign
+	} else if base == 16 {
+		// Must have exponent.
+		return
+	}
+
+	if mantissa != 0 {
+		exp = dp - ndMant
+	}
+	n = i
+
+	if underscores && !underscoreOK(s[:n]) {
+		return
+	}
+
+	ok = true
+	return
+}
+
+func (d *decimal) set(s []byte) (ok bool) {
+	i := 0
+	d.neg = false
+	d.trunc = false
+	sawdot := false
+	sawdigits := false
+
+loop:
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_':
+			continue
+
+		case c == '+':
+			continue
+
+		case c == '-':
+			d.neg = true
+			continue
+
+		case c == '.':
+			if sawdot {
+				break loop
+			}
+			sawdot = true
+			d.dp = d.nd
+			continue
+
+		case '0' <= c && c <= '9':
+			sawdigits = true
+			if c == '0' && d.nd == 0 { // ignore leading zeros
+				d.dp--
+				continue
+			}
+			if d.nd < len(d.d) {
+				d.d[d.nd] = c
+				d.nd++
+			} else if c != '0' {
+				d.trunc = true
+			}
+			continue
+		}
+		break loop
+	}
+	if !sawdigits {
+		return false
+	}
+	if !sawdot {
+		d.dp = d.nd
+	}
+
+	if i < len(s) && lower(s[i]) == 'e' {
+		i++
+		if i >= len(s) {
+			return false
+		}
+		esign := 1
+		if s[i] == '+' {
+			i++
+		} else if s[i] == '-' {
+			i++
+			esign = -1
+		}
+		if i >= len(s) || s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		e := 0
+		for ; i < len(s) && ('0' <= s[i] && s[i] <= '9' || s[i] == '_'); i++ {
+			if s[i] == '_' {
+				continue
+			}
+			if e < 10000 {
+				e = e*10 + int(s[i]-'0')
+			}
+		}
+		d.dp += e * esign
+	}
+
+	return i == len(s)
+}
+
+// powtab[i] is the binary shift count (floor(i*log2(10)), except powtab[0]
+// = 1) that floatBits applies to a decimal whose decimal point is i places
+// away from the [0.5, 1) range, moving it toward that range without
+// overshooting.
+var powtab = []int{1, 3, 6, 9, 13, 16, 19, 23, 26}
+
+func (d *decimal) floatBits(flt *floatInfo) (b uint64, overflow bool) {
+	var exp int
+	var mant uint64
+
+	// Zero is always a special case.
+	if d.nd == 0 {
+		mant = 0
+		exp = flt.bias
+		goto out
+	}
+
+	// Obvious overflow/underflow.
+	// These bounds are for 64-bit floats; they're conservative enough to
+	// also be correct for 32-bit floats.
+	if d.dp > 310 {
+		goto overflow
+	}
+	if d.dp < -330 {
+		// zero
+		mant = 0
+		exp = flt.bias
+		goto out
+	}
+
+	// Scale by powers of two until in range [0.5, 1.0)
+	exp = 0
+	for d.dp > 0 {
+		var n int
+		if d.dp >= len(powtab) {
+			n = 27
+		} else {
+			n = powtab[d.dp]
+		}
+		d.Shift(-n)
+		exp += n
+	}
+	for d.dp < 0 || d.dp == 0 && d.d[0] < '5' {
+		var n int
+		if -d.dp >= len(powtab) {
+			n = 27
+		} else {
+			n = powtab[-d.dp]
+		}
+		d.Shift(n)
+		exp -= n
+	}
+
+	// Our range is [0.5,1) but floating point range is [1,2).
+	exp--
+
+	// Minimum representable exponent is flt.bias+1.
+	// If the exponent is smaller, move it up and adjust d accordingly.
+	if exp < flt.bias+1 {
+		n := flt.bias + 1 - exp
+		d.Shift(-n)
+		exp += n
+	}
+
+	if exp-flt.bias >= 1<<flt.expbits-1 {
+		goto overflow
+	}
+
+	// Extract 1+flt.mantbits bits.
+	d.Shift(int(flt.mantbits + 1))
+	mant = d.RoundedInteger()
+
+	// Rounding might have added a bit; shift down.
+	if mant == 2<<flt.mantbits {
+		mant >>= 1
+		exp++
+		if exp-flt.bias >= 1<<flt.expbits-1 {
+			goto overflow
+		}
+	}
+
+	// Denormalized?
+	if mant&(1<<flt.mantbits) == 0 {
+		exp = flt.bias
+	}
+	goto out
+
+overflow:
+	// ±Inf
+	mant = 0
+	exp = 1<<flt.expbits - 1 + flt.bias
+
+out:
+	// Assemble bits.
+	bits := mant & (uint64(1)<<flt.mantbits - 1)
+	bits |= uint64((exp-flt.bias)&(1<<flt.expbits-1)) << flt.mantbits
+	if d.neg {
+		bits |= 1 << flt.mantbits << flt.expbits
+	}
+	return bits, exp == 1<<flt.expbits-1+flt.bias
+}
+
+// atofHexBits converts a hexadecimal mantissa/exponent pair, as produced by
+// readFloat for "0x"-prefixed input, into the unsigned-magnitude bit pattern
+// for the floating-point format described by flt, rounding to nearest with
+// ties to even. The returned bits never have the sign bit set; callers
+// combine them with the parsed sign via setSign.
+func atofHexBits(flt *floatInfo, mantissa uint64, exp int, trunc bool) (bits uint64, overflow bool) {
+	if mantissa == 0 {
+		return 0, false
+	}
+
+	// Normalize so bit 63 of mantissa is set; the value is then
+	// mantissa * 2**exp, with mantissa/2**63 in [1,2).
+	for mantissa < 1<<63 {
+		mantissa <<= 1
+		exp--
+	}
+	binExp := exp + 63
+
+	// Minimum representable exponent is flt.bias+1; shift subnormal values
+	// down, folding the shifted-out bits into a sticky bit.
+	if binExp < flt.bias+1 {
+		n := uint(flt.bias + 1 - binExp)
+		var sticky uint64
+		if trunc || (n < 64 && mantissa&(uint64(1)<<n-1) != 0) || n >= 64 {
+			sticky = 1
+		}
+		if n >= 64 {
+			mantissa = sticky
+		} else {
+			mantissa = mantissa>>n | sticky
+		}
+		binExp += int(n)
+	}
+
+	// Extract 1+mantbits bits, rounding the rest away to nearest, ties to
+	// even.
+	shift := 63 - flt.mantbits
+	round := (mantissa >> (shift - 1)) & 1
+	sticky := trunc || mantissa&(uint64(1)<<(shift-1)-1) != 0
+	mant := mantissa >> shift
+	if round == 1 && (sticky || mant&1 == 1) {
+		mant++
+		if mant == 2<<flt.mantbits {
+			mant >>= 1
+			binExp++
+		}
+	}
+
+	if binExp-flt.bias >= 1<<flt.expbits-1 {
+		// ±Inf
+		return uint64(1<<flt.expbits-1) << flt.mantbits, true
+	}
+
+	// Denormalized?
+	if mant&(1<<flt.mantbits) == 0 {
+		binExp = flt.bias
+	}
+
+	bits = mant & (uint64(1)<<flt.mantbits - 1)
+	bits |= uint64((binExp-flt.bias)&(1<<flt.expbits-1)) << flt.mantbits
+	return bits, false
+}
@@ -132,11 +132,36 @@ var atoftests = []atofTest{
 	{[]byte("1e-18446744073709551616"), []byte("0"), nil},
 	{[]byte("1e+18446744073709551616"), []byte("+Inf"), ErrRange},
 
+	// Hexadecimal floating-point literals (Go 1.13 syntax).
+	{[]byte("0x1p-1"), []byte("0.5"), nil},
+	{[]byte("0x1p-01"), []byte("0.5"), nil},
+	{[]byte("0x1ep-1"), []byte("15"), nil},
+	{[]byte("0x0.fp4"), []byte("15"), nil},
+	{[]byte("0x1fFe2.p0"), []byte("131042"), nil},
+	{[]byte("0X1P0"), []byte("1"), nil},
+	{[]byte("-0x1p0"), []byte("-1"), nil},
+	{[]byte("0x1p2000"), []byte("+Inf"), ErrRange},
+	{[]byte("-0x1p2000"), []byte("-Inf"), ErrRange},
+
+	// Underscore digit separators (Go 1.13 syntax), accepted only between
+	// digits or between a base prefix and a digit.
+	{[]byte("1_0.5"), []byte("10.5"), nil},
+	{[]byte("1_0_0"), []byte("100"), nil},
+	{[]byte("0x1_ep-1"), []byte("15"), nil},
+	{[]byte("1__0"), []byte("0"), ErrSyntax}, // doubled underscore
+	{[]byte("0x_1p0"), []byte("1"), nil},     // underscore between base prefix and digit is fine
+	{[]byte("_1"), []byte("0"), ErrSyntax},   // leading underscore
+	{[]byte("1_"), []byte("0"), ErrSyntax},   // trailing underscore
+	{[]byte("1._5"), []byte("0"), ErrSyntax}, // underscore adjacent to '.'
+	{[]byte("1e_5"), []byte("0"), ErrSyntax}, // underscore adjacent to 'e'
+
 	// Parse errors
 	{[]byte("1e"), []byte("0"), ErrSyntax},
 	{[]byte("1e-"), []byte("0"), ErrSyntax},
 	{[]byte(".e-1"), []byte("0"), ErrSyntax},
 	{[]byte("1\x00.2"), []byte("0"), ErrSyntax},
+	{[]byte("0x1e2"), []byte("0"), ErrSyntax}, // hex mantissa needs a 'p' exponent
+	{[]byte("1p2"), []byte("0"), ErrSyntax},   // 'p' exponent needs a "0x" mantissa
 
 	// http://www.exploringbinary.com/java-hangs-when-converting-2-2250738585072012e-308/
 	{[]byte("2.2250738585072012e-308"), []byte("2.2250738585072014e-308"), nil},
@@ -231,13 +256,13 @@ func initAtofOnce() {
 	for i := range atoftests {
 		test := &atoftests[i]
 		if test.err != nil {
-			test.err = &NumError{"ParseFloat", test.in, test.err}
+			test.err = NewNumError("ParseFloat", test.in, test.err)
 		}
 	}
 	for i := range atof32tests {
 		test := &atof32tests[i]
 		if test.err != nil {
-			test.err = &NumError{"ParseFloat", test.in, test.err}
+			test.err = NewNumError("ParseFloat", test.in, test.err)
 		}
 	}
 
@@ -365,6 +390,33 @@ func TestRoundTrip(t *testing.T) {
 	}
 }
 
+// TestFormatFloatShortestVsSlow checks that the Ryu-based shortest path in
+// FormatFloat agrees byte-for-byte with the decimal/big-int shortest path on
+// random float64 bit patterns.
+func TestFormatFloatShortestVsSlow(t *testing.T) {
+	n := 100000
+	if testing.Short() {
+		n = 1000
+	}
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < n; i++ {
+		bits := uint64(rng.Uint32())<<32 | uint64(rng.Uint32())
+		f := math.Float64frombits(bits)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			continue
+		}
+
+		SetOptimize(false)
+		slow := FormatFloat(f, 'g', -1, 64)
+		SetOptimize(true)
+		fast := FormatFloat(f, 'g', -1, 64)
+
+		if !bytes.Equal(fast, slow) {
+			t.Fatalf("FormatFloat(%b): fast=%s slow=%s", f, fast, slow)
+		}
+	}
+}
+
 // TestRoundTrip32 tries a fraction of all finite positive float32 values.
 func TestRoundTrip32(t *testing.T) {
 	step := uint32(997)
@@ -394,6 +446,73 @@ func TestRoundTrip32(t *testing.T) {
 	t.Logf("tested %d float32's", count)
 }
 
+// atofHexFormatTests checks that hex float literals parse and then
+// round-trip back through FormatFloat's 'x' verb.
+var atofHexFormatTests = []atofTest{
+	{[]byte("0x1p-1"), []byte("0x1p-01"), nil},
+	{[]byte("1"), []byte("0x1p+00"), nil},
+	{[]byte("0x1ep-1"), []byte("0x1.ep+03"), nil},
+}
+
+func TestAtofHex(t *testing.T) {
+	for _, test := range atofHexFormatTests {
+		f, err := ParseFloat(test.in, 64)
+		if err != nil {
+			t.Errorf("ParseFloat(%s, 64) gave error %s", test.in, err)
+			continue
+		}
+		got := FormatFloat(f, 'x', -1, 64)
+		if !bytes.Equal(got, test.out) {
+			t.Errorf("FormatFloat(ParseFloat(%s), 'x', -1, 64) = %s, want %s", test.in, got, test.out)
+		}
+	}
+}
+
+// fmtXPrecTests exercises FormatFloat's 'x'/'X' verbs with an explicit prec
+// both below and above the number of nibbles the mantissa actually holds
+// (13 for float64, 6 for float32); every existing 'x' test above only used
+// prec == -1, so this is the case that once overflowed frac's uint64 when
+// padding out the extra zero digits.
+var fmtXPrecTests = []struct {
+	bits    uint64
+	bitSize int
+	prec    int
+	verb    byte
+	want    string
+}{
+	{0x4f54e36e7f3627fb, 64, 0, 'x', "0x1p+246"},
+	{0x4f54e36e7f3627fb, 64, 0, 'X', "0X1P+246"},
+	{0x4f54e36e7f3627fb, 64, 3, 'x', "0x1.4e3p+246"},
+	{0x4f54e36e7f3627fb, 64, 3, 'X', "0X1.4E3P+246"},
+	{0x4f54e36e7f3627fb, 64, 18, 'x', "0x1.4e36e7f3627fb00000p+246"},
+	{0x4f54e36e7f3627fb, 64, 18, 'X', "0X1.4E36E7F3627FB00000P+246"},
+	{0x4f54e36e7f3627fb, 64, 25, 'x', "0x1.4e36e7f3627fb000000000000p+246"},
+	{0x4f54e36e7f3627fb, 64, 25, 'X', "0X1.4E36E7F3627FB000000000000P+246"},
+	{0x3ff0000000000000, 64, 3, 'x', "0x1.000p+00"},
+	{0x3ff0000000000000, 64, 18, 'x', "0x1.000000000000000000p+00"},
+	{0x3ff0000000000000, 64, 25, 'x', "0x1.0000000000000000000000000p+00"},
+	{0x40490fdb, 32, 0, 'x', "0x1p+02"},
+	{0x40490fdb, 32, 3, 'x', "0x1.922p+01"},
+	{0x40490fdb, 32, 18, 'x', "0x1.921fb6000000000000p+01"},
+	{0x40490fdb, 32, 25, 'x', "0x1.921fb60000000000000000000p+01"},
+}
+
+func TestFormatFloatXPrec(t *testing.T) {
+	for _, test := range fmtXPrecTests {
+		var f float64
+		if test.bitSize == 64 {
+			f = math.Float64frombits(test.bits)
+		} else {
+			f = float64(math.Float32frombits(uint32(test.bits)))
+		}
+		got := FormatFloat(f, test.verb, test.prec, test.bitSize)
+		if string(got) != test.want {
+			t.Errorf("FormatFloat(%#x, %q, %d, %d) = %s, want %s",
+				test.bits, test.verb, test.prec, test.bitSize, got, test.want)
+		}
+	}
+}
+
 func BenchmarkAtof64Decimal(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		ParseFloat([]byte("33909"), 64)
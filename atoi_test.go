@@ -0,0 +1,107 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/hnakamur/bytesconv"
+)
+
+type parseUint64Test struct {
+	in  []byte
+	out uint64
+	err error
+}
+
+var parseUint64Tests = []parseUint64Test{
+	{[]byte("0"), 0, nil},
+	{[]byte("1"), 1, nil},
+	{[]byte("12345"), 12345, nil},
+	{[]byte("012345"), 5349, nil},  // leading zero means octal in base 0
+	{[]byte("0o12345"), 5349, nil}, // explicit octal prefix, same value
+	{[]byte("12345x"), 0, ErrSyntax},
+	{[]byte("98765432100"), 98765432100, nil},
+	{[]byte("18446744073709551615"), 1<<64 - 1, nil},
+	{[]byte("18446744073709551616"), 1<<64 - 1, ErrRange},
+	{[]byte("18446744073709551620"), 1<<64 - 1, ErrRange},
+	{[]byte(""), 0, ErrSyntax},
+	{[]byte("1_000"), 1000, nil},
+	{[]byte("1_000_000"), 1000000, nil},
+	{[]byte("_1000"), 0, ErrSyntax},
+	{[]byte("1000_"), 0, ErrSyntax},
+	{[]byte("1__000"), 0, ErrSyntax},
+	{[]byte("0b_1010"), 10, nil},
+	{[]byte("0x_1p"), 0, ErrSyntax}, // not valid hex digits
+}
+
+type parseInt64Test struct {
+	in  []byte
+	out int64
+	err error
+}
+
+var parseInt64Tests = []parseInt64Test{
+	{[]byte("0"), 0, nil},
+	{[]byte("-0"), 0, nil},
+	{[]byte("+1"), 1, nil},
+	{[]byte("-1"), -1, nil},
+	{[]byte("12345"), 12345, nil},
+	{[]byte("-12345"), -12345, nil},
+	{[]byte("9223372036854775807"), 1<<63 - 1, nil},
+	{[]byte("9223372036854775808"), 1<<63 - 1, ErrRange},
+	{[]byte("-9223372036854775808"), -1 << 63, nil},
+	{[]byte("-9223372036854775809"), -1 << 63, ErrRange},
+	{[]byte("1_000_000"), 1000000, nil},
+	{[]byte("-1_000_000"), -1000000, nil},
+	{[]byte("1_0_0"), 100, nil}, // underscores may repeat, one per digit boundary
+}
+
+func init() {
+	for i := range parseUint64Tests {
+		test := &parseUint64Tests[i]
+		if test.err != nil {
+			test.err = NewNumError("ParseUint", test.in, test.err)
+		}
+	}
+	for i := range parseInt64Tests {
+		test := &parseInt64Tests[i]
+		if test.err != nil {
+			test.err = NewNumError("ParseInt", test.in, test.err)
+		}
+	}
+}
+
+func TestParseUint(t *testing.T) {
+	for i := range parseUint64Tests {
+		test := &parseUint64Tests[i]
+		out, err := ParseUint(test.in, 0, 64)
+		if out != test.out || !reflect.DeepEqual(err, test.err) {
+			t.Errorf("ParseUint(%q, 0, 64) = %v, %v want %v, %v", test.in, out, err, test.out, test.err)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	for i := range parseInt64Tests {
+		test := &parseInt64Tests[i]
+		out, err := ParseInt(test.in, 0, 64)
+		if out != test.out || !reflect.DeepEqual(err, test.err) {
+			t.Errorf("ParseInt(%q, 0, 64) = %v, %v want %v, %v", test.in, out, err, test.out, test.err)
+		}
+	}
+}
+
+func TestParseIntFixedBaseRejectsUnderscore(t *testing.T) {
+	// Underscores are only accepted in the base==0 (prefix-driven) form;
+	// a fixed base must reject them even between digits.
+	if _, err := ParseInt([]byte("1_000"), 10, 64); err == nil {
+		t.Errorf("ParseInt(\"1_000\", 10, 64) succeeded, want ErrSyntax")
+	}
+	if _, err := ParseUint([]byte("1_000"), 10, 64); err == nil {
+		t.Errorf("ParseUint(\"1_000\", 10, 64) succeeded, want ErrSyntax")
+	}
+}
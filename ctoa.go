@@ -0,0 +1,35 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv
+
+// FormatComplex converts the complex number c to a []byte of the
+// form (a+bi) where a and b are the real and imaginary parts,
+// formatted per the FormatFloat format, precision and bitSize rules.
+//
+// The bitSize argument specifies c's type: 64 for complex64, or 128 for
+// complex128; it must be 64 or 128.
+func FormatComplex(c complex128, fmt byte, prec, bitSize int) []byte {
+	if bitSize != 64 && bitSize != 128 {
+		panic("invalid bitSize")
+	}
+	size := 64
+	if bitSize == 64 {
+		size = 32
+	}
+
+	re := FormatFloat(real(c), fmt, prec, size)
+	im := FormatFloat(imag(c), fmt, prec, size)
+
+	var buf []byte
+	buf = append(buf, '(')
+	buf = append(buf, re...)
+	// The imaginary part always has an explicit sign.
+	if im[0] != '+' && im[0] != '-' {
+		buf = append(buf, '+')
+	}
+	buf = append(buf, im...)
+	buf = append(buf, 'i', ')')
+	return buf
+}
@@ -0,0 +1,96 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv
+
+import "math/bits"
+
+// This implements the Eisel-Lemire ParseFloat fast path, published in 2020
+// and discussed extensively at
+// https://nigeltao.github.io/blog/2020/eisel-lemire.html
+//
+// eisel_lemire_table.go holds pow10Table, pow10Min and pow10Max, generated
+// by make_tables.go (run `go run make_tables.go` to regenerate).
+
+// eiselLemire converts a decimal mantissa and exponent, as produced by
+// readFloat for non-hexadecimal input with no digits dropped to trunc, into
+// the unsigned-magnitude bit pattern for the floating-point format
+// described by flt, without the allocation and digit-by-digit shifting of
+// the decimal slow path.
+//
+// It multiplies the 64-bit mantissa by a precomputed 128-bit approximation
+// of the matching power of ten, then rounds the top bits to flt's mantissa
+// width. Because the table entry is rounded down rather than exact, the
+// rounding direction is only trustworthy when the discarded bits are
+// unambiguously above or below the halfway point; eiselLemire reports
+// ok=false whenever that can't be established (decimal exponent outside the
+// table, an unresolvable tie, or a result on the subnormal/overflow
+// boundary) and the caller must fall back to the decimal/big-int path.
+func eiselLemire(flt *floatInfo, mantissa uint64, exp10 int, neg bool) (bits_ uint64, ok bool) {
+	if mantissa == 0 {
+		return 0, false
+	}
+	if exp10 < pow10Min || exp10 > pow10Max {
+		return 0, false
+	}
+	ent := pow10Table[exp10-pow10Min]
+
+	clz := bits.LeadingZeros64(mantissa)
+	man := mantissa << uint(clz)
+
+	// xHi:xLo approximates man*(10**exp10), scaled so its most significant
+	// bit sits at position 126 or 127; exp2 below accounts for that scale.
+	xHi, xLo := bits.Mul64(man, ent.hi)
+
+	// maskLow is the set of low bits of xHi that, together with xLo, must
+	// all be 1 for the rounded-down table entry to leave real doubt about
+	// the rounding direction.
+	shiftMsb0 := 61 - flt.mantbits
+	maskLow := uint64(1)<<shiftMsb0 - 1
+
+	if xHi&maskLow == maskLow && xLo+man < man {
+		// The single-limb approximation can't resolve the rounding; bring
+		// in the table's low limb for a wider approximation.
+		yHi, yLo := bits.Mul64(man, ent.lo)
+		mergedLo, carry := bits.Add64(xLo, yHi, 0)
+		mergedHi := xHi + carry
+		if mergedHi&maskLow == maskLow && mergedLo+1 == 0 && yLo+man < man {
+			// Still ambiguous even with the wider approximation: only the
+			// exact decimal/big-int path can settle it.
+			return 0, false
+		}
+		xHi, xLo = mergedHi, mergedLo
+	}
+
+	// msb tells whether the product landed in the top or bottom half of
+	// [2**126, 2**128); exp2 is the binary exponent of man's most
+	// significant bit under that scaling.
+	msb := xHi >> 63
+	exp2 := uint64(217706*exp10>>16+64-flt.bias) - uint64(clz) - (1 - msb)
+
+	mant := xHi >> (msb + uint64(shiftMsb0))
+	if xLo == 0 && xHi&maskLow == 0 && mant&3 == 1 {
+		// Exact tie: the truncated table entry isn't enough to trust either
+		// rounding direction, so defer to the decimal slow path.
+		return 0, false
+	}
+
+	// Round the kept mantissa to flt.mantbits+1 bits, to even on ties.
+	mant += mant & 1
+	mant >>= 1
+	if mant>>(flt.mantbits+1) > 0 {
+		mant >>= 1
+		exp2++
+	}
+
+	maxExp := uint64(1)<<flt.expbits - 1
+	if exp2-1 >= maxExp-1 {
+		// Subnormal or overflow boundary: let the decimal slow path, which
+		// already handles these precisely, take over.
+		return 0, false
+	}
+
+	bits_ = exp2<<flt.mantbits | mant&(uint64(1)<<flt.mantbits-1)
+	return setSign(bits_, neg, flt), true
+}
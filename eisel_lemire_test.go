@@ -0,0 +1,54 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	. "github.com/hnakamur/bytesconv"
+)
+
+// TestEiselLemireVsSlow checks that ParseFloat with the Eisel-Lemire fast
+// path enabled agrees with the decimal slow path on random mantissa/exponent
+// pairs, including ones outside the fast path's table window.
+func TestEiselLemireVsSlow(t *testing.T) {
+	testEiselLemireVsSlow(t, 64, 1)
+}
+
+// TestEiselLemireVsSlow32 is the float32 counterpart of
+// TestEiselLemireVsSlow: the fast path is generalized over flt.mantbits, and
+// the 32-bit mask/shift arithmetic in shiftMsb0/maskLow needs its own
+// randomized coverage rather than relying on the 64-bit case to exercise it.
+func TestEiselLemireVsSlow32(t *testing.T) {
+	testEiselLemireVsSlow(t, 32, 2)
+}
+
+func testEiselLemireVsSlow(t *testing.T, bitSize int, seed int64) {
+	n := 10000
+	if testing.Short() {
+		n = 100
+	}
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		digits := 1 + rng.Intn(19)
+		m := uint64(0)
+		for d := 0; d < digits; d++ {
+			m = m*10 + uint64(rng.Intn(10))
+		}
+		exp := rng.Intn(800) - 400
+		s := []byte(fmt.Sprintf("%de%d", m, exp))
+
+		SetOptimize(false)
+		slow, slowErr := ParseFloat(s, bitSize)
+		SetOptimize(true)
+		fast, fastErr := ParseFloat(s, bitSize)
+
+		if fmt.Sprint(fastErr) != fmt.Sprint(slowErr) || fast != slow {
+			t.Fatalf("ParseFloat(%q, %d): fast=%v(%v) slow=%v(%v)", s, bitSize, fast, fastErr, slow, slowErr)
+		}
+	}
+}
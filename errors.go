@@ -0,0 +1,105 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv
+
+import "errors"
+
+// ErrRange indicates that a value is out of range for the target type.
+var ErrRange = errors.New("value out of range")
+
+// ErrSyntax indicates that a value does not have the right syntax for the
+// target type.
+var ErrSyntax = errors.New("invalid syntax")
+
+// A NumError records a failed conversion.
+type NumError struct {
+	Func string // the failing function (ParseInt, ParseUint, ParseFloat, ParseComplex)
+	Err  error  // the reason the conversion failed (e.g. ErrRange, ErrSyntax, etc.)
+	num  []byte // the input, use Num to access
+}
+
+func (e *NumError) Error() string {
+	return "bytesconv." + e.Func + ": " + "parsing " + quoteBytes(e.num) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the reason the conversion failed, so that
+// errors.Is(err, bytesconv.ErrRange) and errors.Is(err, bytesconv.ErrSyntax)
+// work on the *NumError returned by the Parse functions.
+func (e *NumError) Unwrap() error {
+	return e.Err
+}
+
+// Num returns a copy of the input that failed to convert. It is a copy,
+// rather than the []byte the caller originally passed to Parse, so that
+// neither party can corrupt the other's view of it by mutating the result.
+func (e *NumError) Num() []byte {
+	return append([]byte(nil), e.num...)
+}
+
+func syntaxError(fn string, s []byte) *NumError {
+	return &NumError{fn, ErrSyntax, append([]byte(nil), s...)}
+}
+
+func rangeError(fn string, s []byte) *NumError {
+	return &NumError{fn, ErrRange, append([]byte(nil), s...)}
+}
+
+func baseError(fn string, s []byte, base int) *NumError {
+	return &NumError{fn, errors.New("invalid base " + itoaSmall(base)), append([]byte(nil), s...)}
+}
+
+func bitSizeError(fn string, s []byte, bitSize int) *NumError {
+	return &NumError{fn, errors.New("invalid bit size " + itoaSmall(bitSize)), append([]byte(nil), s...)}
+}
+
+// itoaSmall formats small ints, negative ones included, without pulling in
+// fmt.
+func itoaSmall(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+	var buf [20]byte
+	i := len(buf)
+	for n > 0 {
+		i--
+		buf[i] = byte('0' + n%10)
+		n /= 10
+	}
+	if neg {
+		i--
+		buf[i] = '-'
+	}
+	return string(buf[i:])
+}
+
+// quoteBytes is a tiny stand-in for strconv.Quote so that this package does
+// not need to import strconv just to format error messages.
+func quoteBytes(s []byte) string {
+	buf := make([]byte, 0, len(s)+2)
+	buf = append(buf, '"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if c < 0x20 || c == 0x7f {
+				const hex = "0123456789abcdef"
+				buf = append(buf, '\\', 'x', hex[c>>4], hex[c&0xf])
+			} else {
+				buf = append(buf, c)
+			}
+		}
+	}
+	buf = append(buf, '"')
+	return string(buf)
+}
@@ -0,0 +1,53 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/hnakamur/bytesconv"
+)
+
+func TestNumErrorUnwrap(t *testing.T) {
+	rangeErr := NewNumError("ParseFloat", []byte("1e1000"), ErrRange)
+	if !errors.Is(rangeErr, ErrRange) {
+		t.Errorf("errors.Is(%v, ErrRange) = false, want true", rangeErr)
+	}
+	if errors.Is(rangeErr, ErrSyntax) {
+		t.Errorf("errors.Is(%v, ErrSyntax) = true, want false", rangeErr)
+	}
+
+	syntaxErr := NewNumError("ParseFloat", []byte("abc"), ErrSyntax)
+	if !errors.Is(syntaxErr, ErrSyntax) {
+		t.Errorf("errors.Is(%v, ErrSyntax) = false, want true", syntaxErr)
+	}
+	if errors.Is(syntaxErr, ErrRange) {
+		t.Errorf("errors.Is(%v, ErrRange) = true, want false", syntaxErr)
+	}
+}
+
+func TestNumErrorNumIsDefensiveCopy(t *testing.T) {
+	in := []byte("abc")
+	err := NewNumError("ParseFloat", in, ErrSyntax)
+
+	num := err.Num()
+	if string(num) != "abc" {
+		t.Fatalf("Num() = %q, want %q", num, "abc")
+	}
+
+	// Mutating the slice returned by Num must not affect the NumError, and
+	// mutating the caller's original input must not affect the NumError
+	// either: num is a copy in both directions.
+	num[0] = 'x'
+	if got := string(err.Num()); got != "abc" {
+		t.Errorf("Num() after mutating returned slice = %q, want %q", got, "abc")
+	}
+
+	in[0] = 'z'
+	if got := string(err.Num()); got != "abc" {
+		t.Errorf("Num() after mutating original input = %q, want %q", got, "abc")
+	}
+}
@@ -0,0 +1,11 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv
+
+// NewNumError builds a *NumError for use by tests in bytesconv_test, which
+// cannot set NumError's unexported num field directly.
+func NewNumError(fn string, num []byte, err error) *NumError {
+	return &NumError{fn, err, append([]byte(nil), num...)}
+}
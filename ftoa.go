@@ -0,0 +1,530 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Binary to decimal (and to hexadecimal) floating point conversion.
+// Algorithm:
+//   1) store mantissa in multiprecision decimal
+//   2) shift decimal by exponent
+//   3) read digits out & format
+
+package bytesconv
+
+import "math"
+
+const lowerHexDigits = "0123456789abcdef"
+const upperHexDigits = "0123456789ABCDEF"
+
+// FormatFloat converts the floating-point number f to a []byte, according to
+// the format fmt and precision prec. It rounds the result assuming that the
+// original was obtained from a floating-point value of bitSize bits (32 for
+// float32, 64 for float64).
+//
+// The format fmt is one of:
+//
+//	'e' (-d.dddde±dd, a decimal exponent),
+//	'E' (-d.ddddE±dd, a decimal exponent),
+//	'f' (-ddd.dddd, no exponent),
+//	'g' ('e' for large exponents, 'f' otherwise),
+//	'G' ('E' for large exponents, 'f' otherwise),
+//	'x' (-0xd.ddddp±ddd, a hexadecimal fraction and binary exponent), or
+//	'X' (-0Xd.ddddP±ddd, a hexadecimal fraction and binary exponent).
+//
+// The 'b' (binary exponent) format is not supported: for a finite f, any
+// other fmt, including 'b', yields '%' followed by fmt.
+//
+// The precision prec controls the number of digits (excluding the exponent)
+// printed by the 'e', 'E', 'f', 'g', 'G', 'x', and 'X' formats. For 'e', 'E',
+// 'f', 'x', and 'X', it is the number of digits after the decimal point. For
+// 'g' and 'G' it is the maximum number of significant digits (trailing
+// zeros are removed). The special precision -1 uses the smallest number of
+// digits necessary such that ParseFloat will return f exactly.
+func FormatFloat(f float64, fmt byte, prec, bitSize int) []byte {
+	return genericFtoa(make([]byte, 0, max(prec+4, 24)), f, fmt, prec, bitSize)
+}
+
+// AppendFloat appends the []byte form of the floating-point number f, as
+// generated by FormatFloat, to dst and returns the extended buffer.
+func AppendFloat(dst []byte, f float64, fmt byte, prec, bitSize int) []byte {
+	return genericFtoa(dst, f, fmt, prec, bitSize)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func genericFtoa(dst []byte, val float64, fmt byte, prec, bitSize int) []byte {
+	var bits uint64
+	var flt *floatInfo
+	switch bitSize {
+	case 32:
+		bits = uint64(math.Float32bits(float32(val)))
+		flt = &float32info
+	case 64:
+		bits = math.Float64bits(val)
+		flt = &float64info
+	default:
+		panic("bytesconv: illegal AppendFloat/FormatFloat bitSize")
+	}
+
+	neg := bits>>(flt.expbits+flt.mantbits) != 0
+	exp := int(bits>>flt.mantbits) & (1<<flt.expbits - 1)
+	mant := bits & (uint64(1)<<flt.mantbits - 1)
+
+	switch exp {
+	case 1<<flt.expbits - 1:
+		// Inf, NaN
+		var s []byte
+		switch {
+		case mant != 0:
+			s = []byte("NaN")
+		case neg:
+			s = []byte("-Inf")
+		default:
+			s = []byte("+Inf")
+		}
+		return append(dst, s...)
+
+	case 0:
+		// denormalized
+		exp++
+
+	default:
+		// add implicit top bit
+		mant |= uint64(1) << flt.mantbits
+	}
+	exp += flt.bias
+
+	if fmt == 'x' || fmt == 'X' {
+		return fmtX(dst, prec, fmt, neg, mant, exp, flt)
+	}
+
+	shortest := prec < 0
+	var digs decimalSlice
+	if shortest {
+		if optimize {
+			var buf [32]byte
+			digs.d = buf[:]
+			ryuFtoaShortest(&digs, mant, exp-int(flt.mantbits), flt)
+		} else {
+			var d decimal
+			roundShortest(&d, mant, exp, flt)
+			digs = decimalSlice{d: d.d[:d.nd], nd: d.nd, dp: d.dp}
+		}
+		switch fmt {
+		case 'e', 'E':
+			prec = max(digs.nd-1, 0)
+		case 'f':
+			prec = max(digs.nd-digs.dp, 0)
+		case 'g', 'G':
+			prec = digs.nd
+		}
+	} else {
+		var d decimal
+		d.Assign(mant)
+		d.Shift(exp - int(flt.mantbits))
+		switch fmt {
+		case 'e', 'E':
+			d.Round(prec + 1)
+		case 'f':
+			d.Round(d.dp + prec)
+		case 'g', 'G':
+			if prec == 0 {
+				prec = 1
+			}
+			d.Round(prec)
+		}
+		digs = decimalSlice{d: d.d[:d.nd], nd: d.nd, dp: d.dp}
+	}
+	return formatDigits(dst, shortest, neg, digs, prec, fmt)
+}
+
+func formatDigits(dst []byte, shortest bool, neg bool, d decimalSlice, prec int, fmt byte) []byte {
+	switch fmt {
+	case 'e', 'E':
+		return fmtE(dst, neg, d, prec, fmt)
+	case 'f':
+		return fmtF(dst, neg, d, prec)
+	case 'g', 'G':
+		// trailing fractional zeros in 'e' form will be trimmed.
+		eprec := prec
+		if eprec > d.nd && d.nd >= d.dp {
+			eprec = d.nd
+		}
+		// %e is used if the exponent from the conversion
+		// is less than -4 or greater than or equal to the precision.
+		// if precision was the shortest possible, use precision 6 for this decision.
+		if shortest {
+			eprec = 6
+		}
+		exp := d.dp - 1
+		if exp < -4 || exp >= eprec {
+			if prec > d.nd {
+				prec = d.nd
+			}
+			return fmtE(dst, neg, d, prec-1, fmt+'e'-'g')
+		}
+		if prec > d.dp {
+			prec = d.nd
+		}
+		return fmtF(dst, neg, d, max(prec-d.dp, 0))
+	}
+
+	// unknown format
+	return append(dst, '%', fmt)
+}
+
+// roundShortest rounds d (= mant * 2**(exp-flt.mantbits)) to the shortest
+// number of digits that will let the original floating-point value be
+// precisely reconstructed.
+func roundShortest(d *decimal, mant uint64, exp int, flt *floatInfo) {
+	// If mantissa is zero, the number is zero; stop now.
+	if mant == 0 {
+		d.nd = 0
+		return
+	}
+
+	// d = mant << (exp - mantbits), computed exactly.
+	d.Assign(mant)
+	d.Shift(exp - int(flt.mantbits))
+
+	// Compute upper and lower such that any decimal number between them
+	// (possibly inclusive) will round to the original floating-point value.
+	//
+	// We may see at once that the number is already shortest.
+	//
+	// Suppose d is not denormal, so that 2^exp <= d < 10^dp.
+	// The closest shorter number is at least 10^(dp-nd) away.
+	// The lower/upper bounds computed below are at distance
+	// at most 2^(exp-mantbits).
+	//
+	// So the number is already shortest if 10^(dp-nd) > 2^(exp-mantbits),
+	// or equivalently log2(10)*(dp-nd) > exp-mantbits.
+	// It is true if 332/100*(dp-nd) >= exp-mantbits (log2(10) > 3.32).
+	minexp := flt.bias + 1 // minimum possible exponent
+	if exp > minexp && 332*(d.dp-d.nd) >= 100*(exp-int(flt.mantbits)) {
+		// The number is already shortest.
+		return
+	}
+
+	// d = mant << (exp - mantbits)
+	// Next highest floating point number is mant+1 << exp-mantbits.
+	// Our upper bound is halfway between, mant*2+1 << exp-mantbits-1.
+	upper := new(decimal)
+	upper.Assign(mant*2 + 1)
+	upper.Shift(exp - int(flt.mantbits) - 1)
+
+	// d = mant << (exp - mantbits)
+	// Next lowest floating point number is mant-1 << exp-mantbits,
+	// unless mant-1 drops the significant bit and exp is not the minimum exp,
+	// in which case the next lowest is mant*2-1 << exp-mantbits-1.
+	// Either way, call it mantlo << explo-mantbits.
+	// Our lower bound is halfway between, mantlo*2+1 << explo-mantbits-1.
+	var mantlo uint64
+	var explo int
+	if mant > 1<<flt.mantbits || exp == minexp {
+		mantlo = mant - 1
+		explo = exp
+	} else {
+		mantlo = mant*2 - 1
+		explo = exp - 1
+	}
+	lower := new(decimal)
+	lower.Assign(mantlo*2 + 1)
+	lower.Shift(explo - int(flt.mantbits) - 1)
+
+	// The upper and lower bounds are possible outputs only if
+	// the original mantissa is even, so that IEEE round-to-even
+	// rounds to the original mantissa and not to the neighbors.
+	inclusive := mant%2 == 0
+
+	// As we walk the digits we want to know whether rounding up would fall
+	// within the upper bound. This is tracked by upperdelta:
+	//
+	// If upperdelta == 0, the digits of d and upper are the same so far.
+	//
+	// If upperdelta == 1, we saw a difference of 1 between d and upper on a
+	// previous digit and subsequently only 9s for d and 0s for upper.
+	// (Thus rounding up may fall outside the bound, if it is exclusive.)
+	//
+	// If upperdelta == 2, then the difference is greater than 1
+	// and we know that rounding up falls within the bound.
+	var upperdelta uint8
+
+	// Now we can figure out the minimum number of digits required.
+	// Walk along until d has distinguished itself from upper and lower.
+	for ui := 0; ; ui++ {
+		// lower, d, and upper may have the decimal points at different
+		// places. In this case upper is the longest, so we iterate from
+		// ui==0 and start li and mi at (possibly) -1.
+		mi := ui - upper.dp + d.dp
+		if mi >= d.nd {
+			break
+		}
+		li := ui - upper.dp + lower.dp
+		l := byte('0') // lower digit
+		if li >= 0 && li < lower.nd {
+			l = lower.d[li]
+		}
+		m := byte('0') // middle digit
+		if mi >= 0 {
+			m = d.d[mi]
+		}
+		u := byte('0') // upper digit
+		if ui < upper.nd {
+			u = upper.d[ui]
+		}
+
+		// Okay to round down (truncate) if lower has a different digit
+		// or if lower is inclusive and is exactly the result of rounding
+		// down (i.e., and we have reached the final digit of lower).
+		okdown := l != m || inclusive && li+1 == lower.nd
+
+		switch {
+		case upperdelta == 0 && m+1 < u:
+			// Example:
+			// m = 12345xxx
+			// u = 12347xxx
+			upperdelta = 2
+		case upperdelta == 0 && m != u:
+			// Example:
+			// m = 12345xxx
+			// u = 12346xxx
+			upperdelta = 1
+		case upperdelta == 1 && (m != '9' || u != '0'):
+			// Example:
+			// m = 1234598x
+			// u = 1234600x
+			upperdelta = 2
+		}
+		// Okay to round up if upper has a different digit and either upper
+		// is inclusive or upper is bigger than the result of rounding up.
+		okup := upperdelta > 0 && (inclusive || upperdelta > 1 || ui+1 < upper.nd)
+
+		// If it's okay to do either, then round to the nearest one.
+		// If it's okay to do only one, do it.
+		switch {
+		case okdown && okup:
+			d.Round(mi + 1)
+			return
+		case okdown:
+			d.RoundDown(mi + 1)
+			return
+		case okup:
+			d.RoundUp(mi + 1)
+			return
+		}
+	}
+}
+
+func fmtE(dst []byte, neg bool, d decimalSlice, prec int, fmt byte) []byte {
+	if neg {
+		dst = append(dst, '-')
+	}
+
+	// first digit
+	ch := byte('0')
+	if d.nd != 0 {
+		ch = d.d[0]
+	}
+	dst = append(dst, ch)
+
+	// .moredigits
+	if prec > 0 {
+		dst = append(dst, '.')
+		i := 1
+		m := min(d.nd, prec+1)
+		if i < m {
+			dst = append(dst, d.d[i:m]...)
+			i = m
+		}
+		for ; i <= prec; i++ {
+			dst = append(dst, '0')
+		}
+	}
+
+	// e±
+	dst = append(dst, fmt)
+	exp := d.dp - 1
+	if d.nd == 0 { // special case: 0 has exponent 0
+		exp = 0
+	}
+	if exp < 0 {
+		ch = '-'
+		exp = -exp
+	} else {
+		ch = '+'
+	}
+	dst = append(dst, ch)
+
+	// dd or ddd
+	switch {
+	case exp < 10:
+		dst = append(dst, '0', byte(exp)+'0')
+	case exp < 100:
+		dst = append(dst, byte(exp/10)+'0', byte(exp%10)+'0')
+	default:
+		dst = append(dst, byte(exp/100)+'0', byte(exp/10)%10+'0', byte(exp%10)+'0')
+	}
+
+	return dst
+}
+
+func fmtF(dst []byte, neg bool, d decimalSlice, prec int) []byte {
+	if neg {
+		dst = append(dst, '-')
+	}
+
+	// integer, padded with zeros as needed.
+	if d.dp > 0 {
+		m := min(d.nd, d.dp)
+		dst = append(dst, d.d[:m]...)
+		for ; m < d.dp; m++ {
+			dst = append(dst, '0')
+		}
+	} else {
+		dst = append(dst, '0')
+	}
+
+	// fraction
+	if prec > 0 {
+		dst = append(dst, '.')
+		for i := 0; i < prec; i++ {
+			ch := byte('0')
+			if j := d.dp + i; 0 <= j && j < d.nd {
+				ch = d.d[j]
+			}
+			dst = append(dst, ch)
+		}
+	}
+
+	return dst
+}
+
+// fmtX formats a hexadecimal floating-point number in the style produced by
+// the 'x'/'X' verbs: [-]0xh.hhhhp±dd, where mant (with any implicit leading
+// bit already folded in by the caller) and exp satisfy
+// value == mant * 2**(exp-flt.mantbits).
+func fmtX(dst []byte, prec int, fmt byte, neg bool, mant uint64, exp int, flt *floatInfo) []byte {
+	if mant == 0 {
+		exp = 0
+	}
+
+	// Subnormal mantissas don't carry the implicit leading 1 bit at
+	// 1<<flt.mantbits, so shift left (decrementing exp to compensate)
+	// until they're normalized like every other value.
+	for mant != 0 && mant&(uint64(1)<<flt.mantbits) == 0 {
+		mant <<= 1
+		exp--
+	}
+
+	hex := lowerHexDigits
+	pChar := byte('p')
+	if fmt == 'X' {
+		hex = upperHexDigits
+		pChar = 'P'
+	}
+
+	hexDigits := int(flt.mantbits+3) / 4
+	pad := uint(hexDigits)*4 - flt.mantbits
+
+	lead := byte('0')
+	if mant&(uint64(1)<<flt.mantbits) != 0 {
+		lead = '1'
+	}
+	frac := (mant &^ (uint64(1) << flt.mantbits)) << pad
+
+	// digits is how many nibbles of frac are significant; hexDigits is how
+	// many nibbles to print. They diverge when prec asks for more digits
+	// than the mantissa has (frac is padded out with '0' below) — frac
+	// itself is never widened, which would overflow uint64.
+	digits := hexDigits
+	shortest := prec < 0
+	if !shortest {
+		if prec < hexDigits {
+			// Round away the low (hexDigits-prec) nibbles, ties to even.
+			shift := uint(hexDigits-prec) * 4
+			half := uint64(1) << (shift - 1)
+			lo := frac & (uint64(1)<<shift - 1)
+			frac >>= shift
+			if lo > half || (lo == half && frac&1 != 0) {
+				frac++
+			}
+			if frac == uint64(1)<<(uint(prec)*4) {
+				frac = 0
+				if lead == '1' {
+					exp++
+				} else {
+					lead = '1'
+				}
+			}
+			digits = prec
+		}
+		hexDigits = prec
+	}
+
+	if neg {
+		dst = append(dst, '-')
+	}
+	dst = append(dst, '0', fmt, lead)
+
+	if shortest {
+		for hexDigits > 0 && frac&0xf == 0 {
+			frac >>= 4
+			hexDigits--
+		}
+		digits = hexDigits
+	}
+
+	if hexDigits > 0 {
+		dst = append(dst, '.')
+		for i := 0; i < hexDigits; i++ {
+			if i >= digits {
+				dst = append(dst, '0')
+				continue
+			}
+			shift := uint(digits-1-i) * 4
+			dst = append(dst, hex[(frac>>shift)&0xf])
+		}
+	}
+
+	dst = append(dst, pChar)
+	e := exp
+	if e < 0 {
+		dst = append(dst, '-')
+		e = -e
+	} else {
+		dst = append(dst, '+')
+	}
+	if e < 10 {
+		dst = append(dst, '0', byte(e)+'0')
+	} else {
+		dst = appendPositiveInt(dst, e)
+	}
+	return dst
+}
+
+// appendPositiveInt appends the decimal digits of n (n >= 0) to dst.
+func appendPositiveInt(dst []byte, n int) []byte {
+	if n == 0 {
+		return append(dst, '0')
+	}
+	var buf [20]byte
+	i := len(buf)
+	for n > 0 {
+		i--
+		buf[i] = byte('0' + n%10)
+		n /= 10
+	}
+	return append(dst, buf[i:]...)
+}
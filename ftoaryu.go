@@ -0,0 +1,339 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytesconv
+
+import "math/bits"
+
+// Shortest-digit-count binary-to-decimal conversion using the Ryū
+// algorithm.
+//
+// See Ulf Adams, "Ryū: Fast Float-to-String Conversion"
+// (doi:10.1145/3192366.3192369).
+//
+// This file covers only the prec==-1 ("as many digits as needed to
+// round-trip") mode of FormatFloat; explicit-precision formatting still
+// goes through the decimal/big-int path in ftoa.go. It reuses pow10Table
+// from eisel_lemire_table.go: both algorithms need the same rounded-down
+// 128-bit approximations of the powers of ten.
+
+// decimalSlice is a decimal digit string d[:nd] (big-endian) with an
+// implied decimal point after the first dp digits, backed by caller-owned
+// storage so the shortest path can run without allocating.
+type decimalSlice struct {
+	d  []byte
+	nd int
+	dp int
+}
+
+// ryuFtoaShortest formats mant*2**exp, writing the shortest decimal digit
+// string that round-trips back to the original float into d.
+func ryuFtoaShortest(d *decimalSlice, mant uint64, exp int, flt *floatInfo) {
+	if mant == 0 {
+		d.nd, d.dp = 0, 0
+		return
+	}
+	// If the input is an exact integer with fewer bits than the mantissa,
+	// the previous and next integer are not admissible representations.
+	if exp <= 0 && bits.TrailingZeros64(mant) >= -exp {
+		mant >>= uint(-exp)
+		ryuDigits(d, mant, mant, mant, true, false)
+		return
+	}
+	ml, mc, mu, e2 := computeBounds(mant, exp, flt)
+	if e2 == 0 {
+		ryuDigits(d, ml, mc, mu, true, false)
+		return
+	}
+	// Find 10**q larger than 2**-e2.
+	q := mulByLog2Log10(-e2) + 1
+
+	// Multiply by 10**q using 128-bit arithmetic; the exponent is the same
+	// for all three numbers.
+	var dl, dc, du uint64
+	var dl0, dc0, du0 bool
+	if flt == &float32info {
+		var dl32, dc32, du32 uint32
+		dl32, _, dl0 = mult64bitPow10(uint32(ml), e2, q)
+		dc32, _, dc0 = mult64bitPow10(uint32(mc), e2, q)
+		du32, e2, du0 = mult64bitPow10(uint32(mu), e2, q)
+		dl, dc, du = uint64(dl32), uint64(dc32), uint64(du32)
+	} else {
+		dl, _, dl0 = mult128bitPow10(ml, e2, q)
+		dc, _, dc0 = mult128bitPow10(mc, e2, q)
+		du, e2, du0 = mult128bitPow10(mu, e2, q)
+	}
+	if e2 >= 0 {
+		panic("bytesconv: not enough significant bits after mult128bitPow10")
+	}
+	if q > 55 {
+		// Large positive powers of ten are not exact.
+		dl0, dc0, du0 = false, false, false
+	}
+	if q < 0 && q >= -24 {
+		// Division by a power of ten may be exact (5**25 is a 59-bit
+		// number, so division by 5**25 is never exact).
+		if divisibleByPower5(ml, -q) {
+			dl0 = true
+		}
+		if divisibleByPower5(mc, -q) {
+			dc0 = true
+		}
+		if divisibleByPower5(mu, -q) {
+			du0 = true
+		}
+	}
+	// Express (dl, dc, du)*2**e2 as integers, dropping the extra low bits
+	// and keeping rounding hints.
+	extra := uint(-e2)
+	extraMask := uint64(1)<<extra - 1
+	dl, fracl := dl>>extra, dl&extraMask
+	dc, fracc := dc>>extra, dc&extraMask
+	du, fracu := du>>extra, du&extraMask
+	// 'du' is usable as a result whenever it was truncated, or when it's
+	// exact and the original binary mantissa was even; otherwise it must
+	// be backed off by one.
+	uok := !du0 || fracu > 0
+	if du0 && fracu == 0 {
+		uok = mant&1 == 0
+	}
+	if !uok {
+		du--
+	}
+	// Does 'dc' need to round up to 'dc'+1?
+	cup := false
+	if dc0 {
+		// Exact product: a half-integer remainder rounds to even.
+		cup = fracc > 1<<(extra-1) ||
+			(fracc == 1<<(extra-1) && dc&1 == 1)
+	} else {
+		// Truncated product: a half-or-more remainder always rounds up.
+		cup = fracc>>(extra-1) == 1
+	}
+	// 'dl' is usable only if it's exact and the original mantissa was
+	// even; otherwise it must be nudged up by one.
+	lok := dl0 && fracl == 0 && mant&1 == 0
+	if !lok {
+		dl++
+	}
+	c0 := dc0 && fracc == 0
+	ryuDigits(d, dl, dc, du, c0, cup)
+	d.dp -= q
+}
+
+// mulByLog2Log10 returns floor(x * log(2)/log(10)) for x in [-1600, 1600].
+func mulByLog2Log10(x int) int {
+	// log(2)/log(10) ≈ 0.30102999566 ≈ 78913 / 2**18
+	return (x * 78913) >> 18
+}
+
+// mulByLog10Log2 returns floor(x * log(10)/log(2)) for x in [-500, 500].
+func mulByLog10Log2(x int) int {
+	// log(10)/log(2) ≈ 3.32192809489 ≈ 108853 / 2**15
+	return (x * 108853) >> 15
+}
+
+// computeBounds returns (lower, central, upper)*2**e2: 55-bit (26-bit for
+// float32) integer mantissas for mant*2**exp and the bounds of the interval
+// of real numbers that round to it. Both bounds belong to that interval
+// when mant is even and neither does when mant is odd.
+func computeBounds(mant uint64, exp int, flt *floatInfo) (lower, central, upper uint64, e2 int) {
+	if mant != 1<<flt.mantbits || exp == flt.bias+1-int(flt.mantbits) {
+		// Regular case (or denormals).
+		lower, central, upper = 2*mant-1, 2*mant, 2*mant+1
+		e2 = exp - 1
+		return
+	}
+	// Border of an exponent: the lower neighbor is twice as close as usual.
+	lower, central, upper = 4*mant-1, 4*mant, 4*mant+2
+	e2 = exp - 2
+	return
+}
+
+// mult64bitPow10 multiplies a 25-bit-mantissa float by 10**q, returning a
+// result mantissa of m*P>>57 (typically 31 or 32 bits wide), where P is the
+// table's 64-bit hi limb. exact reports whether all trimmed bits were zero.
+func mult64bitPow10(m uint32, e2, q int) (resM uint32, resE int, exact bool) {
+	if q == 0 {
+		return m << 6, e2 - 6, true
+	}
+	if q < pow10Min || pow10Max < q {
+		// Never happens given the float32/float64 exponent range.
+		panic("bytesconv: mult64bitPow10: power of 10 out of range")
+	}
+	pow := pow10Table[q-pow10Min].hi
+	if q < 0 {
+		// Inverse powers of ten must be rounded up.
+		pow++
+	}
+	hi, lo := bits.Mul64(uint64(m), pow)
+	e2 += mulByLog10Log2(q) - 63 + 57
+	return uint32(hi<<7 | lo>>57), e2, lo<<7 == 0
+}
+
+// mult128bitPow10 multiplies a 55-bit-mantissa float by 10**q, returning a
+// result mantissa of m*P>>119 (typically 63 or 64 bits wide), where P is
+// the table's 128-bit (hi, lo) limbs. exact reports whether all trimmed
+// bits were zero.
+func mult128bitPow10(m uint64, e2, q int) (resM uint64, resE int, exact bool) {
+	if q == 0 {
+		return m << 8, e2 - 8, true
+	}
+	if q < pow10Min || pow10Max < q {
+		// Never happens given the float32/float64 exponent range.
+		panic("bytesconv: mult128bitPow10: power of 10 out of range")
+	}
+	ent := pow10Table[q-pow10Min]
+	lo := ent.lo
+	if q < 0 {
+		// Inverse powers of ten must be rounded up.
+		lo++
+	}
+	e2 += mulByLog10Log2(q) - 127 + 119
+
+	l1, l0 := bits.Mul64(m, lo)
+	h1, h0 := bits.Mul64(m, ent.hi)
+	mid, carry := bits.Add64(l1, h0, 0)
+	h1 += carry
+	return h1<<9 | mid>>55, e2, mid<<9 == 0 && l0 == 0
+}
+
+func divisibleByPower5(m uint64, k int) bool {
+	if m == 0 {
+		return true
+	}
+	for i := 0; i < k; i++ {
+		if m%5 != 0 {
+			return false
+		}
+		m /= 5
+	}
+	return true
+}
+
+// divmod1e9 computes the quotient and remainder of x divided by 1e9.
+func divmod1e9(x uint64) (uint32, uint32) {
+	return uint32(x / 1e9), uint32(x % 1e9)
+}
+
+// ryuDigits renders the shared decimal digits of lower, central and upper
+// into d, choosing central's value (rounded per c0/cup) except where that
+// would conflict with the open lower/upper bounds.
+func ryuDigits(d *decimalSlice, lower, central, upper uint64, c0, cup bool) {
+	lhi, llo := divmod1e9(lower)
+	chi, clo := divmod1e9(central)
+	uhi, ulo := divmod1e9(upper)
+	if uhi == 0 {
+		// Only low digits (for denormals).
+		ryuDigits32(d, llo, clo, ulo, c0, cup, 8)
+	} else if lhi < uhi {
+		// Truncate 9 digits at once.
+		if llo != 0 {
+			lhi++
+		}
+		c0 = c0 && clo == 0
+		cup = (clo > 5e8) || (clo == 5e8 && cup)
+		ryuDigits32(d, lhi, chi, uhi, c0, cup, 8)
+		d.dp += 9
+	} else {
+		d.nd = 0
+		// Emit the high part.
+		n := uint(9)
+		for v := chi; v > 0; {
+			v1, v2 := v/10, v%10
+			v = v1
+			n--
+			d.d[n] = byte(v2 + '0')
+		}
+		d.d = d.d[n:]
+		d.nd = int(9 - n)
+		// Emit the low part.
+		ryuDigits32(d, llo, clo, ulo, c0, cup, d.nd+8)
+	}
+	// Trim trailing zeros.
+	for d.nd > 0 && d.d[d.nd-1] == '0' {
+		d.nd--
+	}
+	// Trim leading zeros.
+	for d.nd > 0 && d.d[0] == '0' {
+		d.nd--
+		d.dp--
+		d.d = d.d[1:]
+	}
+}
+
+// ryuDigits32 emits the decimal digits of a number less than 1e9, stopping
+// as soon as central is distinguishable from the open lower/upper bounds.
+func ryuDigits32(d *decimalSlice, lower, central, upper uint32, c0, cup bool, endindex int) {
+	if upper == 0 {
+		d.dp = endindex + 1
+		return
+	}
+	trimmed := 0
+	// cNextDigit remembers the last trimmed digit, for the round-up check;
+	// c0 tracks whether every digit trimmed after it was zero.
+	cNextDigit := 0
+	for upper > 0 {
+		// Repeatedly compute:
+		//   l = Ceil(lower / 10**k)
+		//   c = Round(central / 10**k)
+		//   u = Floor(upper / 10**k)
+		// and stop as soon as c would leave the (l, u) interval.
+		l := (lower + 9) / 10
+		c, cdigit := central/10, central%10
+		u := upper / 10
+		if l > u {
+			// Don't trim the last digit, since it can't go below l.
+			break
+		}
+		// l < u but c == l-1 is essentially impossible, but can happen if
+		// lower=..11, central=..19, upper=..31: central is very close to,
+		// but less than, an integer ending in many zeros. The round-up
+		// logic below usually masks it.
+		if l == c+1 && c < u {
+			c++
+			cdigit = 0
+			cup = false
+		}
+		trimmed++
+		c0 = c0 && cNextDigit == 0
+		cNextDigit = int(cdigit)
+		lower, central, upper = l, c, u
+	}
+	if trimmed > 0 {
+		cup = cNextDigit > 5 ||
+			(cNextDigit == 5 && !c0) ||
+			(cNextDigit == 5 && c0 && central&1 == 1)
+	}
+	if central < upper && cup {
+		central++
+	}
+	// The digit count is now known, so fill d directly from the end.
+	endindex -= trimmed
+	v := central
+	n := endindex
+	for n > d.nd {
+		v1, v2 := v/100, v%100
+		d.d[n] = smallsString[2*v2+1]
+		d.d[n-1] = smallsString[2*v2+0]
+		n -= 2
+		v = v1
+	}
+	if n == d.nd {
+		d.d[n] = byte(v + '0')
+	}
+	d.nd = endindex + 1
+	d.dp = d.nd + trimmed
+}
+
+const smallsString = "00010203040506070809" +
+	"10111213141516171819" +
+	"20212223242526272829" +
+	"30313233343536373839" +
+	"40414243444546474849" +
+	"50515253545556575859" +
+	"60616263646566676869" +
+	"70717273747576777879" +
+	"80818283848586878889" +
+	"90919293949596979899"
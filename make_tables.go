@@ -0,0 +1,111 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+
+// This program generates eisel_lemire_table.go, the table of 128-bit
+// approximations of powers of ten consumed by the Eisel-Lemire fast path in
+// eisel_lemire.go. Run it with:
+//
+//	go run make_tables.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"math/big"
+	"os"
+)
+
+// pow10Min and pow10Max bound the decimal exponents covered by the table.
+// They comfortably span the exponents produced by readFloat for both
+// float32 and float64 inputs that have no truncated digits.
+const (
+	pow10Min = -348
+	pow10Max = 347
+)
+
+func main() {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by make_tables.go. DO NOT EDIT.\n\n")
+	buf.WriteString("package bytesconv\n\n")
+	fmt.Fprintf(&buf, "const pow10Min = %d\n", pow10Min)
+	fmt.Fprintf(&buf, "const pow10Max = %d\n\n", pow10Max)
+	buf.WriteString("// pow10Table[q-pow10Min] holds the top 128 bits (hi, lo) of 5**q, rounded\n")
+	buf.WriteString("// down and normalized so that hi's top bit is set. Rounding down (rather\n")
+	buf.WriteString("// than to nearest) matters: eiselLemire's wider-approximation fallback\n")
+	buf.WriteString("// relies on the truncation error always being non-negative. 10**q itself is\n")
+	buf.WriteString("// hi:lo scaled by a power of two that eiselLemire derives from q directly,\n")
+	buf.WriteString("// rather than a per-entry field, since normalization makes the scale a\n")
+	buf.WriteString("// function of q alone. Generated by make_tables.go.\n")
+	buf.WriteString("var pow10Table = [pow10Max - pow10Min + 1]struct {\n\thi, lo uint64\n}{\n")
+
+	for q := pow10Min; q <= pow10Max; q++ {
+		hi, lo := pow10Entry(q)
+		fmt.Fprintf(&buf, "\t{0x%016x, 0x%016x}, // q=%d\n", hi, lo, q)
+	}
+	buf.WriteString("}\n")
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("eisel_lemire_table.go", out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// pow10Entry returns the 128-bit value of 5**q, rounded down and normalized
+// into [2**127, 2**128), as hi:lo.
+func pow10Entry(q int) (hi, lo uint64) {
+	five := big.NewInt(5)
+	pow5 := new(big.Int).Exp(five, big.NewInt(int64(abs(q))), nil)
+
+	num := big.NewInt(1)
+	den := big.NewInt(1)
+	if q >= 0 {
+		num = pow5
+	} else {
+		den = pow5
+	}
+
+	// val is 5**q * 2**k rounded down to the nearest integer; eiselLemire's
+	// wider-approximation fallback relies on the truncation error always
+	// being non-negative.
+	k := 128 + bitLen(den) - bitLen(num)
+	var val *big.Int
+	for {
+		var n, d *big.Int
+		if k >= 0 {
+			n = new(big.Int).Lsh(num, uint(k))
+			d = den
+		} else {
+			n = num
+			d = new(big.Int).Lsh(den, uint(-k))
+		}
+		val = new(big.Int).Div(n, d)
+		switch bl := val.BitLen(); {
+		case bl < 128:
+			k++
+		case bl > 128:
+			k--
+		default:
+			mask64 := new(big.Int).SetUint64(^uint64(0))
+			loBig := new(big.Int).And(val, mask64)
+			hiBig := new(big.Int).Rsh(val, 64)
+			return hiBig.Uint64(), loBig.Uint64()
+		}
+	}
+}
+
+func bitLen(x *big.Int) int { return x.BitLen() }
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}